@@ -4,7 +4,6 @@ package conf
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -12,7 +11,6 @@ import (
 	"strings"
 
 	"github.com/MG-RAST/golib/goconfig/config"
-	"gopkg.in/yaml.v2"
 )
 
 // Location set of storage locations
@@ -46,9 +44,6 @@ type TSMLocation struct {
 	Recoverycommand string `bson:"recoverycommand" json:"recoverycommand" yaml:"Recoverycommand" `
 }
 
-// LocationsMap allow access to Location objects via Locations("ID")
-var LocationsMap map[string]*LocationConfig
-
 // Config contains an array of Location objects
 type Config struct {
 	Locations []LocationConfig `bson:"Locations" json:"Locations" yaml:"Locations" `
@@ -69,7 +64,7 @@ var VERSIONS = map[string]int{
 	"Node": 4,
 }
 
-var LOG_OUTPUTS = [3]string{"file", "console", "both"}
+var LOG_OUTPUTS = [4]string{"file", "console", "both", "webhook"}
 
 var (
 	// Admin
@@ -115,6 +110,14 @@ var (
 	LOG_TRACE   bool // enable trace logging
 	DEBUG_LEVEL int
 
+	// Logs: webhook target
+	LOG_WEBHOOK_URL            string // endpoint records are POSTed to
+	LOG_WEBHOOK_AUTH           string // Authorization header value, e.g. "Bearer ..."
+	LOG_WEBHOOK_BATCH_SIZE     int    // max records per POST
+	LOG_WEBHOOK_FLUSH_INTERVAL int    // max time a batch waits before being sent, in ms
+	LOG_WEBHOOK_MAX_WORKERS    int    // upper bound on concurrent batch-sending workers
+	LOG_WEBHOOK_QUEUE_SIZE     int    // size of the buffered channel in front of the workers
+
 	// Mongo information
 	MONGODB_HOSTS             string
 	MONGODB_DATABASE          string
@@ -209,17 +212,17 @@ func Initialize() (err error) {
 	}
 
 	// read Locations.yaml file from same directory as config file
-	var LocationsPath = path.Dir(CONFIG_FILE)
-	LocationsPath = path.Join(LocationsPath, "Locations.yaml")
+	locationsFilePath = path.Join(path.Dir(CONFIG_FILE), "Locations.yaml")
 
-	fmt.Printf("read Locations file: %s\n", LocationsPath)
+	fmt.Printf("read Locations file: %s\n", locationsFilePath)
 
 	// we should check the YAML config file for correctness and schema compliance
 	// TOBEADDED --> https://github.com/santhosh-tekuri/jsonschema/issues/5
-	err = readYAMLConfig(LocationsPath)
+	locs, err := loadLocationsFile(locationsFilePath)
 	if err != nil {
 		return errors.New("error reading Locations file: " + err.Error())
 	}
+	setLocations(locs)
 	return
 }
 
@@ -307,6 +310,12 @@ func getConfiguration(c *config.Config) (c_store *Config_store, err error) {
 	c_store.AddString(&LOG_OUTPUT, "both", "Log", "logoutput", "console, file or both", "")
 	c_store.AddBool(&LOG_TRACE, false, "Log", "trace", "", "")
 	c_store.AddInt(&DEBUG_LEVEL, 0, "Log", "debuglevel", "debug level: 0-3", "")
+	c_store.AddString(&LOG_WEBHOOK_URL, "", "Log", "webhook_url", "", "endpoint to POST log records to when logoutput=webhook")
+	c_store.AddString(&LOG_WEBHOOK_AUTH, "", "Log", "webhook_auth", "", "Authorization header sent with each webhook POST")
+	c_store.AddInt(&LOG_WEBHOOK_BATCH_SIZE, 100, "Log", "batch_size", "", "max number of log records per webhook POST")
+	c_store.AddInt(&LOG_WEBHOOK_FLUSH_INTERVAL, 1000, "Log", "flush_interval", "", "max time in ms a batch waits before being sent")
+	c_store.AddInt(&LOG_WEBHOOK_MAX_WORKERS, 4, "Log", "max_workers", "", "max number of concurrent webhook sender workers")
+	c_store.AddInt(&LOG_WEBHOOK_QUEUE_SIZE, 10000, "Log", "queue_size", "", "size of the buffered channel in front of the webhook workers")
 
 	// Mongodb
 	c_store.AddString(&MONGODB_ATTRIBUTE_INDEXES, "", "Mongodb", "attribute_indexes", "", "")
@@ -424,31 +433,3 @@ func cleanPath(p string) string {
 	}
 	return p
 }
-
-// readYAMLConfig read a YAML style config file with Shock configuration
-// the file has to be a yaml file, currently for Locations only
-func readYAMLConfig(filename string) (err error) {
-
-	var conf Config
-
-	source, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return (err)
-	}
-	err = yaml.Unmarshal(source, &conf)
-	if err != nil {
-		return (err)
-	}
-
-	// create a global
-	//var Locations Locations
-	LocationsMap = make(map[string]*LocationConfig)
-
-	for i, _ := range conf.Locations {
-		loc := &conf.Locations[i]
-
-		LocationsMap[loc.ID] = loc
-	}
-
-	return
-}
@@ -0,0 +1,140 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	locationsMu       sync.RWMutex
+	locationsMap      map[string]*LocationConfig
+	locationsFilePath string
+)
+
+// CheckLocationInUse, when set, is consulted by ReloadLocations before
+// dropping a Location that a reload would remove. It lets the node
+// package (which knows how to query existing nodes) veto a reload
+// without conf importing node/db and creating an import cycle.
+var CheckLocationInUse func(id string) (bool, error)
+
+// Location looks up a single Location by ID.
+func Location(id string) (*LocationConfig, bool) {
+	locationsMu.RLock()
+	defer locationsMu.RUnlock()
+	loc, ok := locationsMap[id]
+	return loc, ok
+}
+
+// Locations returns a snapshot of all known Locations, safe to range
+// over without holding any lock.
+func Locations() []*LocationConfig {
+	locationsMu.RLock()
+	defer locationsMu.RUnlock()
+	locs := make([]*LocationConfig, 0, len(locationsMap))
+	for _, loc := range locationsMap {
+		locs = append(locs, loc)
+	}
+	return locs
+}
+
+func setLocations(m map[string]*LocationConfig) {
+	locationsMu.Lock()
+	defer locationsMu.Unlock()
+	locationsMap = m
+}
+
+// SetLocationsForTest replaces the running LocationsMap directly,
+// bypassing the file read and validation ReloadLocations does. It
+// exists so other packages' tests (e.g. replicator's SelectTargets
+// tests) can set up deterministic Location fixtures without writing a
+// Locations.yaml to disk.
+func SetLocationsForTest(locs map[string]*LocationConfig) {
+	setLocations(locs)
+}
+
+// ReloadLocations re-reads Locations.yaml from the path used at
+// startup, validates it, and atomically swaps it in for the running
+// LocationsMap. Unless force is true, it refuses to drop a Location
+// that CheckLocationInUse reports is still referenced by a node.
+func ReloadLocations(force bool) (err error) {
+	next, err := loadLocationsFile(locationsFilePath)
+	if err != nil {
+		return err
+	}
+
+	added, removed, changed := diffLocations(locationsMap, next)
+
+	if !force && CheckLocationInUse != nil {
+		for _, id := range removed {
+			inUse, cerr := CheckLocationInUse(id)
+			if cerr != nil {
+				return fmt.Errorf("checking whether location %s is in use: %s", id, cerr.Error())
+			}
+			if inUse {
+				return fmt.Errorf("location %s is still referenced by at least one node, use --force to reload anyway", id)
+			}
+		}
+	}
+
+	setLocations(next)
+	logLocationsDiff(added, removed, changed)
+	return nil
+}
+
+// loadLocationsFile reads and validates a Locations.yaml file without
+// touching the running LocationsMap.
+func loadLocationsFile(filename string) (map[string]*LocationConfig, error) {
+	source, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Config
+	if err = yaml.Unmarshal(source, &c); err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]*LocationConfig, len(c.Locations))
+	for i := range c.Locations {
+		loc := &c.Locations[i]
+		if loc.ID == "" {
+			return nil, errors.New("Locations.yaml contains an entry with an empty ID")
+		}
+		if _, dup := m[loc.ID]; dup {
+			return nil, fmt.Errorf("Locations.yaml contains duplicate ID: %s", loc.ID)
+		}
+		m[loc.ID] = loc
+	}
+	return m, nil
+}
+
+// diffLocations compares the currently running map to a freshly loaded
+// one, returning added/removed/changed Location IDs (sorted).
+func diffLocations(old, next map[string]*LocationConfig) (added, removed, changed []string) {
+	for id, loc := range next {
+		prev, ok := old[id]
+		if !ok {
+			added = append(added, id)
+		} else if *prev != *loc {
+			changed = append(changed, id)
+		}
+	}
+	for id := range old {
+		if _, ok := next[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return
+}
+
+func logLocationsDiff(added, removed, changed []string) {
+	fmt.Printf("Locations.yaml reloaded: added=%v removed=%v changed=%v\n", added, removed, changed)
+}
@@ -0,0 +1,106 @@
+package conf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestLocationsFile(t *testing.T, body string) string {
+	dir, err := ioutil.TempDir("", "shock-locations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "Locations.yaml")
+	if err := ioutil.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func withTestLocationsState(t *testing.T, filePath string, initial map[string]*LocationConfig, checkInUse func(id string) (bool, error)) {
+	prevPath, prevMap, prevCheck := locationsFilePath, locationsMap, CheckLocationInUse
+	locationsFilePath = filePath
+	setLocations(initial)
+	CheckLocationInUse = checkInUse
+	t.Cleanup(func() {
+		locationsFilePath, CheckLocationInUse = prevPath, prevCheck
+		setLocations(prevMap)
+	})
+}
+
+func TestReloadLocationsRejectsDroppingInUseLocationWithoutForce(t *testing.T) {
+	path := writeTestLocationsFile(t, `
+Locations:
+  - ID: keep
+    Persistent: true
+`)
+	withTestLocationsState(t, path,
+		map[string]*LocationConfig{
+			"keep":   {ID: "keep", Persistent: true},
+			"retire": {ID: "retire", Persistent: true},
+		},
+		func(id string) (bool, error) { return id == "retire", nil },
+	)
+
+	err := ReloadLocations(false)
+	if err == nil {
+		t.Fatal("expected ReloadLocations to reject dropping an in-use location without force")
+	}
+	if _, ok := Location("retire"); !ok {
+		t.Error("expected the in-use location to remain after a rejected reload")
+	}
+}
+
+func TestReloadLocationsSucceedsWithForce(t *testing.T) {
+	path := writeTestLocationsFile(t, `
+Locations:
+  - ID: keep
+    Persistent: true
+`)
+	withTestLocationsState(t, path,
+		map[string]*LocationConfig{
+			"keep":   {ID: "keep", Persistent: true},
+			"retire": {ID: "retire", Persistent: true},
+		},
+		func(id string) (bool, error) { return id == "retire", nil },
+	)
+
+	if err := ReloadLocations(true); err != nil {
+		t.Fatalf("ReloadLocations(true): %s", err)
+	}
+	if _, ok := Location("retire"); ok {
+		t.Error("expected the in-use location to be dropped once force is set")
+	}
+	if _, ok := Location("keep"); !ok {
+		t.Error("expected the surviving location to still be present")
+	}
+}
+
+func TestDiffLocationsBucketsAddedRemovedChanged(t *testing.T) {
+	old := map[string]*LocationConfig{
+		"same":    {ID: "same", Priority: 1},
+		"removed": {ID: "removed", Priority: 1},
+		"changed": {ID: "changed", Priority: 1},
+	}
+	next := map[string]*LocationConfig{
+		"same":    {ID: "same", Priority: 1},
+		"changed": {ID: "changed", Priority: 2},
+		"added":   {ID: "added", Priority: 1},
+	}
+
+	added, removed, changed := diffLocations(old, next)
+
+	if len(added) != 1 || added[0] != "added" {
+		t.Errorf("expected added=[added], got %v", added)
+	}
+	if len(removed) != 1 || removed[0] != "removed" {
+		t.Errorf("expected removed=[removed], got %v", removed)
+	}
+	if len(changed) != 1 || changed[0] != "changed" {
+		t.Errorf("expected changed=[changed], got %v", changed)
+	}
+}
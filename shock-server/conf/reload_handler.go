@@ -0,0 +1,36 @@
+package conf
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ListenForSIGHUP watches for SIGHUP and reloads Locations.yaml on
+// receipt. The server's main loop starts this as a goroutine during
+// startup, alongside InitReaper and friends.
+func ListenForSIGHUP() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	for range sig {
+		if err := ReloadLocations(false); err != nil {
+			fmt.Printf("SIGHUP: failed to reload Locations.yaml: %s\n", err.Error())
+		}
+	}
+}
+
+// ReloadLocationsHandler serves POST /admin/reload-locations. It takes
+// the same force semantics as ReloadLocations via a "force" query
+// parameter, for operators who have confirmed dropping an in-use
+// Location is intentional.
+func ReloadLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	force := r.URL.Query().Get("force") == "true"
+	if err := ReloadLocations(force); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "Locations.yaml reloaded")
+}
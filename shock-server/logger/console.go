@@ -0,0 +1,16 @@
+package logger
+
+import "fmt"
+
+// ConsoleTarget writes Records to stdout.
+type ConsoleTarget struct{}
+
+// NewConsoleTarget creates a ConsoleTarget.
+func NewConsoleTarget() ConsoleTarget {
+	return ConsoleTarget{}
+}
+
+func (c ConsoleTarget) Write(r Record) error {
+	fmt.Printf("[%s] %s %s\n", r.Level, r.Time.Format("2006-01-02 15:04:05"), r.Message)
+	return nil
+}
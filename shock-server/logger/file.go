@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileTarget appends Records to a log file under the given directory.
+type FileTarget struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileTarget opens (creating if needed) shock-server.log under dir.
+func NewFileTarget(dir string) (*FileTarget, error) {
+	f, err := os.OpenFile(filepath.Join(dir, "shock-server.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileTarget{f: f}, nil
+}
+
+func (t *FileTarget) Write(r Record) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := fmt.Fprintf(t.f, "[%s] %s %s\n", r.Level, r.Time.Format("2006-01-02 15:04:05"), r.Message)
+	return err
+}
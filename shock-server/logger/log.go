@@ -0,0 +1,90 @@
+// Package logger provides the shock-server logging facility, selectable
+// at startup via conf.LOG_OUTPUT between console, file, both or webhook
+// targets.
+package logger
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MG-RAST/Shock/shock-server/conf"
+)
+
+// Record is a single log entry handed to a Target.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+	Perf    *PerfLog  `json:"perf,omitempty"`
+}
+
+// Target receives log Records. Implementations must be safe for
+// concurrent use.
+type Target interface {
+	Write(r Record) error
+}
+
+var output Target
+
+// Initialize selects the logging Target based on conf.LOG_OUTPUT.
+func Initialize() (err error) {
+	output, err = newTarget(conf.LOG_OUTPUT)
+	return
+}
+
+func newTarget(name string) (Target, error) {
+	switch name {
+	case "console":
+		return NewConsoleTarget(), nil
+	case "file":
+		return NewFileTarget(conf.PATH_LOGS)
+	case "both":
+		f, err := NewFileTarget(conf.PATH_LOGS)
+		if err != nil {
+			return nil, err
+		}
+		return MultiTarget{NewConsoleTarget(), f}, nil
+	case "webhook":
+		return NewWebhookTarget(), nil
+	}
+	return nil, fmt.Errorf("invalid log output target: %s", name)
+}
+
+func log(level, message string) {
+	if output == nil {
+		output = NewConsoleTarget()
+	}
+	output.Write(Record{Time: time.Now(), Level: level, Message: message})
+}
+
+// Infof logs a formatted informational message.
+func Infof(format string, v ...interface{}) {
+	log("INFO", fmt.Sprintf(format, v...))
+}
+
+// Info logs an informational message.
+func Info(message string) {
+	log("INFO", message)
+}
+
+// Errorf logs a formatted error message.
+func Errorf(format string, v ...interface{}) {
+	log("ERROR", fmt.Sprintf(format, v...))
+}
+
+// Error logs an error message.
+func Error(message string) {
+	log("ERROR", message)
+}
+
+// MultiTarget fans a Record out to every contained Target.
+type MultiTarget []Target
+
+func (m MultiTarget) Write(r Record) error {
+	for _, t := range m {
+		if err := t.Write(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"time"
+
+	"github.com/MG-RAST/Shock/shock-server/conf"
+)
+
+// PerfLog records how long a labeled operation took. Targets that care
+// about performance data (e.g. webhook) forward it alongside the Record;
+// others are free to ignore the field.
+type PerfLog struct {
+	Label     string `json:"label"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+}
+
+// Perf logs a PerfLog entry for an operation that started at start.
+func Perf(label string, start time.Time) {
+	if !conf.LOG_PERF {
+		return
+	}
+	r := Record{
+		Time:    time.Now(),
+		Level:   "PERF",
+		Message: label,
+		Perf: &PerfLog{
+			Label:     label,
+			ElapsedMs: time.Since(start).Milliseconds(),
+		},
+	}
+	if output == nil {
+		output = NewConsoleTarget()
+	}
+	output.Write(r)
+}
@@ -0,0 +1,187 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MG-RAST/Shock/shock-server/conf"
+)
+
+// WebhookTarget ships Records to a remote HTTP endpoint as
+// newline-delimited JSON. Records are buffered on a channel and drained
+// by a pool of worker goroutines that batch up to BatchSize records (or
+// FlushInterval, whichever comes first) into a single POST.
+//
+// Workers are spun up lazily, bounded by MaxWorkers: Write checks the
+// live worker count before deciding to spawn another, so a full buffer
+// grows the pool instead of spinning or blocking the caller. Once the
+// buffer is full and MaxWorkers is already reached, records are dropped
+// and counted rather than blocking.
+//
+// liveWorkers is guarded by mu rather than left as a bare atomic,
+// because the "am I the last worker" exit decision in worker() is a
+// check-then-act: reading the count and decrementing it have to happen
+// as one step, or two idle workers can both observe count==2 and both
+// exit, dropping the pool to zero.
+type WebhookTarget struct {
+	url           string
+	auth          string
+	batchSize     int
+	flushInterval time.Duration
+	maxWorkers    int32
+
+	records chan Record
+
+	mu          sync.Mutex
+	liveWorkers int32
+
+	dropped atomic.Uint64
+}
+
+// NewWebhookTarget builds a WebhookTarget from the Log section config
+// keys (webhook_url, webhook_auth, batch_size, max_workers, queue_size)
+// and starts its first worker.
+func NewWebhookTarget() *WebhookTarget {
+	t := &WebhookTarget{
+		url:           conf.LOG_WEBHOOK_URL,
+		auth:          conf.LOG_WEBHOOK_AUTH,
+		batchSize:     conf.LOG_WEBHOOK_BATCH_SIZE,
+		flushInterval: time.Duration(conf.LOG_WEBHOOK_FLUSH_INTERVAL) * time.Millisecond,
+		maxWorkers:    int32(conf.LOG_WEBHOOK_MAX_WORKERS),
+		records:       make(chan Record, conf.LOG_WEBHOOK_QUEUE_SIZE),
+	}
+	t.spawnWorker()
+	return t
+}
+
+// Write enqueues r for delivery. If the buffer is full it tries to grow
+// the worker pool (up to maxWorkers) before falling back to dropping the
+// record; it never blocks the caller.
+func (t *WebhookTarget) Write(r Record) error {
+	if t.workerCount() == 0 {
+		t.spawnWorker()
+	}
+
+	select {
+	case t.records <- r:
+		return nil
+	default:
+	}
+
+	// check and increment under one lock so concurrent writers cannot
+	// together push the pool past maxWorkers
+	t.mu.Lock()
+	grow := t.liveWorkers < t.maxWorkers
+	if grow {
+		t.liveWorkers++
+	}
+	t.mu.Unlock()
+	if grow {
+		go t.worker()
+	}
+
+	select {
+	case t.records <- r:
+	default:
+		t.dropped.Add(1)
+	}
+	return nil
+}
+
+// Dropped returns the number of records dropped due to sustained
+// back-pressure on the buffer.
+func (t *WebhookTarget) Dropped() uint64 {
+	return t.dropped.Load()
+}
+
+func (t *WebhookTarget) workerCount() int32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.liveWorkers
+}
+
+func (t *WebhookTarget) spawnWorker() {
+	t.mu.Lock()
+	t.liveWorkers++
+	t.mu.Unlock()
+	go t.worker()
+}
+
+// tryExit atomically checks "am I the last worker" and, if not,
+// accounts for this worker leaving in the same critical section — so
+// two idle workers can never both see themselves as dispensable and
+// exit together.
+func (t *WebhookTarget) tryExit() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.liveWorkers <= 1 {
+		return false
+	}
+	t.liveWorkers--
+	return true
+}
+
+// worker batches records off the shared channel and flushes on
+// BatchSize or FlushInterval, whichever is reached first. Workers that
+// sit idle for a few flush intervals exit via tryExit, so liveWorkers
+// tracks actual backlog rather than growing without bound — except the
+// last one, which tryExit refuses to let leave, so there is always at
+// least one worker draining the channel between bursts of traffic.
+func (t *WebhookTarget) worker() {
+	batch := make([]Record, 0, t.batchSize)
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+
+	idleFlushes := 0
+	for {
+		select {
+		case r := <-t.records:
+			batch = append(batch, r)
+			idleFlushes = 0
+			if len(batch) >= t.batchSize {
+				t.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				t.flush(batch)
+				batch = batch[:0]
+				idleFlushes = 0
+				continue
+			}
+			idleFlushes++
+			if idleFlushes > 3 && t.tryExit() {
+				return
+			}
+		}
+	}
+}
+
+func (t *WebhookTarget) flush(batch []Record) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range batch {
+		if err := enc.Encode(r); err != nil {
+			return
+		}
+	}
+
+	req, err := http.NewRequest("POST", t.url, &buf)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if t.auth != "" {
+		req.Header.Set("Authorization", t.auth)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestWebhookTarget(url string) *WebhookTarget {
+	t := &WebhookTarget{
+		url:           url,
+		batchSize:     4,
+		flushInterval: 20 * time.Millisecond,
+		maxWorkers:    4,
+		records:       make(chan Record, 64),
+	}
+	t.spawnWorker()
+	return t
+}
+
+func TestWebhookTargetFlushesBatchToEndpoint(t *testing.T) {
+	var mu sync.Mutex
+	var lines []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scanner := bufio.NewScanner(r.Body)
+		mu.Lock()
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	target := newTestWebhookTarget(srv.URL)
+
+	for i := 0; i < 4; i++ {
+		target.Write(Record{Level: "INFO", Message: "hello"})
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(lines)
+		mu.Unlock()
+		if n >= 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 4 records delivered to the endpoint, got %d", n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if target.Dropped() != 0 {
+		t.Errorf("expected no dropped records under normal operation, got %d", target.Dropped())
+	}
+}
+
+// TestWebhookTargetTryExitNeverDropsBelowOneWorker simulates every
+// worker going idle at the same instant, which previously let a
+// load-then-branch race drop liveWorkers to zero. With the decision
+// synchronized under mu, only all-but-one of N concurrent callers may
+// succeed.
+func TestWebhookTargetTryExitNeverDropsBelowOneWorker(t *testing.T) {
+	target := &WebhookTarget{liveWorkers: 5}
+
+	const attempts = 200
+	var wg sync.WaitGroup
+	var exits int32
+	var exitsMu sync.Mutex
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if target.tryExit() {
+				exitsMu.Lock()
+				exits++
+				exitsMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if exits != 4 {
+		t.Errorf("expected exactly 4 of 5 workers to be allowed to exit, got %d", exits)
+	}
+	if target.workerCount() != 1 {
+		t.Errorf("expected exactly one worker left alive, got %d", target.workerCount())
+	}
+}
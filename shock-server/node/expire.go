@@ -20,22 +20,89 @@ func InitReaper() {
 	Ttl = NewNodeReaper()
 }
 
-type NodeReaper struct{}
+// NodeReaper periodically deletes expired nodes and, in cache mode,
+// evicts locally cached files once its EvictionPolicy says it is safe
+// to do so.
+type NodeReaper struct {
+	interval          time.Duration
+	cacheTTL          time.Duration
+	nodeLockerMaxAge  time.Duration
+	fileLockerMaxAge  time.Duration
+	indexLockerMaxAge time.Duration
+	expireQuery       func() bson.M
+	evictionPolicy    CachePolicy
+}
+
+// Option configures a NodeReaper. See WithInterval, WithCacheTTL,
+// WithLockerMaxAge, WithExpireQuery and WithEvictionPolicy.
+type Option func(*NodeReaper)
+
+// WithInterval sets how long the reaper sleeps between passes.
+func WithInterval(d time.Duration) Option {
+	return func(nr *NodeReaper) { nr.interval = d }
+}
+
+// WithCacheTTL sets the CacheTTL of the default PersistentReplicaPolicy:
+// how long a cached file must sit unused before that policy allows its
+// eviction. It has no effect when WithEvictionPolicy supplies a policy.
+func WithCacheTTL(d time.Duration) Option {
+	return func(nr *NodeReaper) { nr.cacheTTL = d }
+}
+
+// WithLockerMaxAge sets the garbage-collection ages for the node, file
+// and index Lockers, respectively.
+func WithLockerMaxAge(node, file, index time.Duration) Option {
+	return func(nr *NodeReaper) {
+		nr.nodeLockerMaxAge = node
+		nr.fileLockerMaxAge = file
+		nr.indexLockerMaxAge = index
+	}
+}
+
+// WithExpireQuery overrides the Mongo query used to find expired nodes.
+func WithExpireQuery(f func() bson.M) Option {
+	return func(nr *NodeReaper) { nr.expireQuery = f }
+}
+
+// WithEvictionPolicy overrides the CachePolicy used to decide whether a
+// cached node's local file may be evicted.
+func WithEvictionPolicy(policy CachePolicy) Option {
+	return func(nr *NodeReaper) { nr.evictionPolicy = policy }
+}
+
+// NewNodeReaper builds a NodeReaper from conf defaults, then applies
+// opts in order.
+func NewNodeReaper(opts ...Option) *NodeReaper {
+	nr := &NodeReaper{
+		interval:          time.Duration(conf.EXPIRE_WAIT) * time.Minute,
+		cacheTTL:          time.Duration(conf.CACHE_TTL) * time.Hour,
+		nodeLockerMaxAge:  time.Hour,
+		fileLockerMaxAge:  6 * time.Hour,
+		indexLockerMaxAge: 6 * time.Hour,
+	}
+	nr.expireQuery = nr.defaultExpireQuery
 
-func NewNodeReaper() *NodeReaper {
-	return &NodeReaper{}
+	for _, opt := range opts {
+		opt(nr)
+	}
+
+	// build the default policy only if WithEvictionPolicy didn't already
+	// set one, and only after opts has run so WithCacheTTL actually
+	// reaches it
+	if nr.evictionPolicy == nil {
+		nr.evictionPolicy = PersistentReplicaPolicy{CacheTTL: nr.cacheTTL}
+	}
+	return nr
 }
 
 func (nr *NodeReaper) Handle() {
-	waitDuration := time.Duration(conf.EXPIRE_WAIT) * time.Minute
 	for {
-
 		// sleep
-		time.Sleep(waitDuration)
+		time.Sleep(nr.interval)
+
 		// query to get expired nodes
 		nodes := Nodes{}
-		query := nr.getQuery()
-		nodes.GetAll(query)
+		nodes.GetAll(nr.expireQuery())
 		// delete expired nodes
 		for _, n := range nodes {
 			logger.Infof("Deleting expired node: %s", n.Id)
@@ -45,62 +112,38 @@ func (nr *NodeReaper) Handle() {
 			}
 		}
 		// garbage collection: remove old nodes from Lockers, value is hours old
-		locker.NodeLockMgr.RemoveOld(1)
-		locker.FileLockMgr.RemoveOld(6)
-		locker.IndexLockMgr.RemoveOld(6)
+		locker.NodeLockMgr.RemoveOld(int(nr.nodeLockerMaxAge.Hours()))
+		locker.FileLockMgr.RemoveOld(int(nr.fileLockerMaxAge.Hours()))
+		locker.IndexLockMgr.RemoveOld(int(nr.indexLockerMaxAge.Hours()))
 
 		// we do not start deletings files if we are not in cache mode
 		if conf.PATH_CACHE == "" {
 			continue
 		}
-	Loop2:
+
 		// start a FILE REAPER that loops thru CacheMap[*]
 		for ID := range cache.CacheMap {
-
-			//fmt.Printf("(Reaper-->FileReaper) checking %s in cache\n", ID)
-
-			now := time.Now()
-			lru := cache.CacheMap[ID].Access
-			diff := now.Sub(lru)
-
-			// we use a very simple scheme for caching initially (file not used for 1 day)
-			if diff.Hours() < float64(conf.CACHE_TTL) {
-				//	fmt.Printf("(Reaper-->FileReaper) not deleting %s from cache it was last accessed %s hours ago\n", ID, diff.Hours())
-				continue
-			}
-
 			n, err := Load(ID)
 			if err != nil {
 				logger.Infof("(Reaper-->FileReaper) Cannot access CacheMapItem[%s] (%s)", ID, err.Error())
 				continue
 			}
 
-			for _, loc := range n.Locations {
-				// delete only if other locations exist
-				locObj, ok := conf.LocationsMap[loc]
-				if !ok {
-					logger.Errorf("(Reaper-->FileReaper) location %s is not defined in this server instance \n ", loc)
-					continue
-				}
-				//fmt.Printf("(Reaper-->FileReaper) locObj.Persistent =  %b  \n ", locObj.Persistent)
-				if locObj.Persistent == true {
-					logger.Infof("(Reaper-->FileReaper) has remote Location (%s) removing from Cache: %s", loc, ID)
-
-					cache.Remove(ID)
-					continue Loop2 // the innermost loop
-				}
+			evict, reason := nr.evictionPolicy.ShouldEvict(n)
+			if !evict {
+				logger.Infof("(Reaper-->FileReaper) not evicting %s: %s", ID, reason)
+				continue
 			}
-			logger.Errorf("(Reaper-->FileReaper) cannot delete %s from cache [This should not happen!!]", ID)
+
+			logger.Infof("(Reaper-->FileReaper) evicting %s from Cache: %s", ID, reason)
+			cache.Remove(ID)
 		}
 	}
-
-	return
 }
 
-func (nr *NodeReaper) getQuery() (query bson.M) {
+func (nr *NodeReaper) defaultExpireQuery() bson.M {
 	hasExpire := bson.M{"expiration": bson.M{"$exists": true}}   // has the field
 	toExpire := bson.M{"expiration": bson.M{"$ne": time.Time{}}} // value has been set, not default
 	isExpired := bson.M{"expiration": bson.M{"$lt": time.Now()}} // value is too old
-	query = bson.M{"$and": []bson.M{hasExpire, toExpire, isExpired}}
-	return
+	return bson.M{"$and": []bson.M{hasExpire, toExpire, isExpired}}
 }
@@ -0,0 +1,54 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestNewNodeReaperDefaultPolicyUsesConfiguredCacheTTL(t *testing.T) {
+	nr := NewNodeReaper(WithCacheTTL(2 * time.Hour))
+
+	p, ok := nr.evictionPolicy.(PersistentReplicaPolicy)
+	if !ok {
+		t.Fatalf("expected default PersistentReplicaPolicy, got %T", nr.evictionPolicy)
+	}
+	if p.CacheTTL != 2*time.Hour {
+		t.Errorf("WithCacheTTL did not reach the default eviction policy: got %s, want %s", p.CacheTTL, 2*time.Hour)
+	}
+}
+
+func TestNewNodeReaperExplicitPolicyNotOverridden(t *testing.T) {
+	custom := MinReplicaPolicy{N: 5}
+	nr := NewNodeReaper(WithEvictionPolicy(custom), WithCacheTTL(3*time.Hour))
+
+	if nr.evictionPolicy != CachePolicy(custom) {
+		t.Errorf("expected WithEvictionPolicy to survive being set before WithCacheTTL, got %#v", nr.evictionPolicy)
+	}
+}
+
+func TestNewNodeReaperWithExpireQuery(t *testing.T) {
+	called := false
+	fake := func() bson.M {
+		called = true
+		return bson.M{"fake": true}
+	}
+
+	nr := NewNodeReaper(WithExpireQuery(fake))
+	nr.expireQuery()
+
+	if !called {
+		t.Error("expected the fake expire query passed via WithExpireQuery to be used")
+	}
+}
+
+func TestMinReplicaPolicyBelowThresholdIsNotEvicted(t *testing.T) {
+	p := MinReplicaPolicy{N: 2}
+	n := &Node{Id: "node-with-no-known-locations"}
+
+	evict, reason := p.ShouldEvict(n)
+	if evict {
+		t.Errorf("expected node short of N persistent replicas not to be evicted, got reason %q", reason)
+	}
+}
@@ -16,6 +16,7 @@ type column struct {
 	f     *os.File
 	r     line.LineReader
 	Index *Idx
+	Mode  string // "array" (default) or "hash", set by the API caller
 }
 
 func NewColumnIndexer(f *os.File) column {
@@ -30,7 +31,17 @@ func (c *column) Create(string) (count int64, format string, err error) {
 	return
 }
 
+// CreateColumnIndex builds an index over the given column (1-based).
+// When c.Mode is "hash" it builds an on-disk hash index for O(1)
+// exact-match lookups on unsorted data; otherwise (the default) it
+// builds the original sequential "array" index of (offset,length)
+// records grouped by consecutive equal column values, which requires
+// the input to already be sorted on that column.
 func CreateColumnIndex(c *column, column int, ofile string) (count int64, format string, err error) {
+	if c.Mode == "hash" {
+		return CreateHashColumnIndex(c, column, ofile)
+	}
+
 	tmpFilePath := fmt.Sprintf("%s/temp/%d%d.idx", conf.PATH_DATA, rand.Int(), rand.Int())
 
 	f, err := os.Create(tmpFilePath)
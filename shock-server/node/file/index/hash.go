@@ -0,0 +1,264 @@
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+
+	"github.com/MG-RAST/Shock/shock-server/conf"
+	"github.com/OneOfOne/xxhash"
+)
+
+// On-disk layout of a "hash" format index:
+//
+//	header: magic(8) version(uint32) recordSize(uint32) bucketCount(uint64)
+//	bucket table: bucketCount x uint64 file offsets (0 = empty chain)
+//	payload: chained (fingerprint uint64, data_offset uint64, data_length uint32, next uint64) records
+const (
+	hashMagic      = "SHKHIDX1"
+	hashHeaderSize = 24
+	hashRecordSize = 28 // fingerprint(8) + data_offset(8) + data_length(4) + next(8)
+
+	// hashSpillThreshold bounds how many in-memory entries accumulate
+	// before being spilled to a temp chunk file, mirroring the 16MB
+	// buffer used by the array builder.
+	hashSpillThreshold = 16777216 / hashRecordSize
+)
+
+type hashEntry struct {
+	fingerprint uint64
+	dataOffset  int64
+	dataLength  int32
+}
+
+// CreateHashColumnIndex builds an on-disk hash index over the given
+// column (1-based), keyed by xxhash(columnValue), allowing O(1)
+// exact-match lookups via LookupHash without requiring the input to be
+// pre-sorted on that column. Entries are buffered in memory and spilled
+// to temp files once hashSpillThreshold is exceeded, then merged in a
+// single pass that writes the final bucket table and chained payload.
+func CreateHashColumnIndex(c *column, column int, ofile string) (count int64, format string, err error) {
+	format = "hash"
+
+	chunks, total, err := spillHashEntries(c, column)
+	defer func() {
+		for _, chunk := range chunks {
+			os.Remove(chunk)
+		}
+	}()
+	if err != nil {
+		return 0, format, err
+	}
+
+	tmpFilePath := fmt.Sprintf("%s/temp/%d%d.idx", conf.PATH_DATA, rand.Int(), rand.Int())
+	out, err := os.Create(tmpFilePath)
+	if err != nil {
+		return 0, format, err
+	}
+	defer out.Close()
+
+	bucketCount := nextPow2(total/2 + 1)
+	if bucketCount < 16 {
+		bucketCount = 16
+	}
+	bucketHeads := make([]int64, bucketCount)
+
+	if _, err = out.Seek(hashHeaderSize+int64(bucketCount)*8, io.SeekStart); err != nil {
+		return 0, format, err
+	}
+
+	pos := hashHeaderSize + int64(bucketCount)*8
+	rec := make([]byte, hashRecordSize)
+	for _, chunk := range chunks {
+		if err = mergeChunk(chunk, out, bucketHeads, bucketCount, &pos, rec); err != nil {
+			return 0, format, err
+		}
+	}
+
+	// bucket table
+	table := make([]byte, bucketCount*8)
+	for i, head := range bucketHeads {
+		binary.LittleEndian.PutUint64(table[i*8:i*8+8], uint64(head))
+	}
+	if _, err = out.WriteAt(table, hashHeaderSize); err != nil {
+		return 0, format, err
+	}
+
+	// header
+	header := make([]byte, hashHeaderSize)
+	copy(header[0:8], hashMagic)
+	binary.LittleEndian.PutUint32(header[8:12], 1) // version
+	binary.LittleEndian.PutUint32(header[12:16], hashRecordSize)
+	binary.LittleEndian.PutUint64(header[16:24], uint64(bucketCount))
+	if _, err = out.WriteAt(header, 0); err != nil {
+		return 0, format, err
+	}
+
+	if err = out.Close(); err != nil {
+		return 0, format, err
+	}
+	if err = os.Rename(tmpFilePath, ofile); err != nil {
+		return 0, format, err
+	}
+	return total, format, nil
+}
+
+// spillHashEntries scans the data file, computes xxhash(columnValue)
+// for each record and writes entries to one or more temp chunk files,
+// keeping at most hashSpillThreshold entries in memory at a time.
+func spillHashEntries(c *column, column int) (chunks []string, total int64, err error) {
+	buf := make([]hashEntry, 0, hashSpillThreshold)
+	curr := int64(0)
+
+	flush := func() error {
+		if len(buf) == 0 {
+			return nil
+		}
+		path := fmt.Sprintf("%s/temp/%d%d.hidx.chunk", conf.PATH_DATA, rand.Int(), rand.Int())
+		f, ferr := os.Create(path)
+		if ferr != nil {
+			return ferr
+		}
+		defer f.Close()
+
+		var out bytes.Buffer
+		rec := make([]byte, 20)
+		for _, e := range buf {
+			binary.LittleEndian.PutUint64(rec[0:8], e.fingerprint)
+			binary.LittleEndian.PutUint64(rec[8:16], uint64(e.dataOffset))
+			binary.LittleEndian.PutUint32(rec[16:20], uint32(e.dataLength))
+			out.Write(rec)
+		}
+		if _, werr := f.Write(out.Bytes()); werr != nil {
+			return werr
+		}
+		chunks = append(chunks, path)
+		buf = buf[:0]
+		return nil
+	}
+
+	for {
+		line, er := c.r.ReadLine()
+		n := len(line)
+		if er != nil && er != io.EOF {
+			return chunks, total, er
+		}
+		if n > 1 {
+			slices := bytes.Split(line, []byte("\t"))
+			if len(slices) < column-1 {
+				return chunks, total, errors.New("Specified column does not exist for all lines in file.")
+			}
+			key := string(slices[column-1])
+			buf = append(buf, hashEntry{
+				fingerprint: xxhash.ChecksumString64(key),
+				dataOffset:  curr,
+				dataLength:  int32(n),
+			})
+			total++
+			if len(buf) >= hashSpillThreshold {
+				if err = flush(); err != nil {
+					return chunks, total, err
+				}
+			}
+		}
+		curr += int64(n)
+		if er == io.EOF {
+			break
+		}
+	}
+
+	if err = flush(); err != nil {
+		return chunks, total, err
+	}
+	return chunks, total, nil
+}
+
+// mergeChunk streams one spilled chunk file into the output's payload
+// region, prepending each entry to its bucket's chain.
+func mergeChunk(path string, out *os.File, bucketHeads []int64, bucketCount int64, pos *int64, rec []byte) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	raw := make([]byte, 20)
+	for {
+		_, er := io.ReadFull(in, raw)
+		if er != nil {
+			if er == io.EOF {
+				break
+			}
+			return er
+		}
+		fp := binary.LittleEndian.Uint64(raw[0:8])
+		dataOffset := binary.LittleEndian.Uint64(raw[8:16])
+		dataLength := binary.LittleEndian.Uint32(raw[16:20])
+
+		bucket := fp % uint64(bucketCount)
+		binary.LittleEndian.PutUint64(rec[0:8], fp)
+		binary.LittleEndian.PutUint64(rec[8:16], dataOffset)
+		binary.LittleEndian.PutUint32(rec[16:20], dataLength)
+		binary.LittleEndian.PutUint64(rec[20:28], uint64(bucketHeads[bucket]))
+
+		if _, err = out.WriteAt(rec, *pos); err != nil {
+			return err
+		}
+		bucketHeads[bucket] = *pos
+		*pos += hashRecordSize
+	}
+	return nil
+}
+
+// LookupHash returns every (offset,length) record whose column value
+// hashes to key, by hashing the query and walking the bucket's chain.
+// Matching is by fingerprint only, so in the rare case of an xxhash
+// collision a caller doing exact-match filtering should re-check the
+// value at the returned offset.
+func (idx *Idx) LookupHash(key string) ([]Record, error) {
+	if idx.Format != "hash" {
+		return nil, errors.New("LookupHash is only valid for hash format indices")
+	}
+
+	fp := xxhash.ChecksumString64(key)
+	bucket := fp % idx.bucketCount
+
+	headBuf := make([]byte, 8)
+	if _, err := idx.f.ReadAt(headBuf, idx.bucketTableStart+int64(bucket)*8); err != nil {
+		return nil, err
+	}
+	next := int64(binary.LittleEndian.Uint64(headBuf))
+
+	var records []Record
+	rec := make([]byte, idx.recordSize)
+	for next != 0 {
+		if _, err := idx.f.ReadAt(rec, next); err != nil {
+			return nil, err
+		}
+		entryFp := binary.LittleEndian.Uint64(rec[0:8])
+		if entryFp == fp {
+			records = append(records, Record{
+				Offset: int64(binary.LittleEndian.Uint64(rec[8:16])),
+				Length: int64(binary.LittleEndian.Uint32(rec[16:20])),
+			})
+		}
+		next = int64(binary.LittleEndian.Uint64(rec[20:28]))
+	}
+	return records, nil
+}
+
+// nextPow2 returns the smallest power of two >= n (minimum 1).
+func nextPow2(n int64) int64 {
+	if n <= 1 {
+		return 1
+	}
+	p := int64(1)
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
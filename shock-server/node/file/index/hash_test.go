@@ -0,0 +1,86 @@
+package index
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MG-RAST/Shock/shock-server/conf"
+)
+
+func TestHashIndexBuildAndLookupRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shock-hash-idx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, "temp"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	conf.PATH_DATA = dir
+
+	data := "a\tfirst\nb\tsecond\na\tthird\nc\tfourth\n"
+	dataPath := filepath.Join(dir, "data.tab")
+	if err := ioutil.WriteFile(dataPath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(dataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	c := NewColumnIndexer(f)
+	c.Mode = "hash"
+
+	idxPath := filepath.Join(dir, "data.hidx")
+	count, format, err := CreateColumnIndex(&c, 1, idxPath)
+	if err != nil {
+		t.Fatalf("CreateColumnIndex: %s", err)
+	}
+	if format != "hash" {
+		t.Fatalf("expected format %q, got %q", "hash", format)
+	}
+	if count != 4 {
+		t.Fatalf("expected 4 indexed records, got %d", count)
+	}
+
+	idx := New()
+	if err := idx.Open(idxPath); err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer idx.Close()
+	if idx.Format != "hash" {
+		t.Fatalf("expected Open to detect hash format, got %q", idx.Format)
+	}
+
+	raw, err := ioutil.ReadFile(dataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := idx.LookupHash("a")
+	if err != nil {
+		t.Fatalf("LookupHash: %s", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records for key %q, got %d", "a", len(records))
+	}
+	for _, rec := range records {
+		line := raw[rec.Offset : rec.Offset+rec.Length]
+		if len(line) == 0 || line[0] != 'a' {
+			t.Errorf("record at offset %d did not point at an \"a\" line: %q", rec.Offset, line)
+		}
+	}
+
+	none, err := idx.LookupHash("does-not-exist")
+	if err != nil {
+		t.Fatalf("LookupHash: %s", err)
+	}
+	if len(none) != 0 {
+		t.Errorf("expected no records for an unknown key, got %d", len(none))
+	}
+}
@@ -0,0 +1,80 @@
+package index
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+)
+
+// arrayRecordSize is the width in bytes of a single (offset,length)
+// record in the sequential "array" index format.
+const arrayRecordSize = 16
+
+// Record is a single (offset, length) span into the indexed data file.
+type Record struct {
+	Offset int64
+	Length int64
+}
+
+// Idx reads an on-disk index file built by CreateColumnIndex, in either
+// the original sequential "array" format or the "hash" format.
+type Idx struct {
+	Format string
+	f      *os.File
+
+	// hash format fields, populated by Open
+	bucketCount      uint64
+	recordSize       uint32
+	bucketTableStart int64
+}
+
+// New creates an empty, unopened Idx defaulting to the "array" format.
+func New() *Idx {
+	return &Idx{Format: "array"}
+}
+
+// Open associates path's on-disk index file with this Idx, detecting
+// the hash format from its magic header and falling back to the
+// original array format otherwise.
+func (idx *Idx) Open(path string) (err error) {
+	idx.f, err = os.Open(path)
+	if err != nil {
+		return
+	}
+
+	header := make([]byte, hashHeaderSize)
+	n, err := idx.f.ReadAt(header, 0)
+	if err == nil && n == hashHeaderSize && string(header[0:8]) == hashMagic {
+		idx.Format = "hash"
+		idx.recordSize = binary.LittleEndian.Uint32(header[12:16])
+		idx.bucketCount = binary.LittleEndian.Uint64(header[16:24])
+		idx.bucketTableStart = hashHeaderSize
+		return nil
+	}
+
+	idx.Format = "array"
+	return nil
+}
+
+// Get returns the n'th (offset,length) record from an "array" format
+// index.
+func (idx *Idx) Get(n int64) (rec Record, err error) {
+	if idx.Format != "array" {
+		return rec, errors.New("Get is only valid for array format indices")
+	}
+	buf := make([]byte, arrayRecordSize)
+	if _, err = idx.f.ReadAt(buf, n*arrayRecordSize); err != nil {
+		return
+	}
+	rec.Offset = int64(binary.LittleEndian.Uint64(buf[0:8]))
+	rec.Length = int64(binary.LittleEndian.Uint64(buf[8:16]))
+	return
+}
+
+// Close releases the underlying file handle.
+func (idx *Idx) Close() error {
+	if idx.f == nil {
+		return nil
+	}
+	return idx.f.Close()
+}
@@ -0,0 +1,22 @@
+package node
+
+import (
+	"github.com/MG-RAST/Shock/shock-server/conf"
+	"github.com/MG-RAST/Shock/shock-server/db"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func init() {
+	conf.CheckLocationInUse = locationInUse
+}
+
+// locationInUse reports whether any node currently lists id among its
+// Locations, used by conf.ReloadLocations to refuse dropping a Location
+// that is still referenced, absent --force.
+func locationInUse(id string) (bool, error) {
+	count, err := db.Connection.DB.C("Nodes").Find(bson.M{"locations": id}).Count()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,125 @@
+package node
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MG-RAST/Shock/shock-server/cache"
+	"github.com/MG-RAST/Shock/shock-server/conf"
+	"github.com/MG-RAST/Shock/shock-server/logger"
+	"github.com/MG-RAST/Shock/shock-server/replicator"
+)
+
+// CachePolicy decides whether a cached node's local file may be evicted
+// by the NodeReaper's file reaper pass. The returned string is a short
+// human-readable reason, used for logging.
+type CachePolicy interface {
+	ShouldEvict(n *Node) (bool, string)
+}
+
+// withinTTL reports whether n's cached file was accessed more recently
+// than ttl ago. It is shared by the policies below that respect TTL.
+func withinTTL(n *Node, ttl time.Duration) bool {
+	item, ok := cache.CacheMap[n.Id]
+	if !ok {
+		return false
+	}
+	return time.Since(item.Access) < ttl
+}
+
+func persistentLocations(n *Node) []string {
+	locs := []string{}
+	for _, loc := range n.Locations {
+		locObj, ok := conf.Location(loc)
+		if !ok {
+			logger.Errorf("(Reaper-->FileReaper) location %s is not defined in this server instance", loc)
+			continue
+		}
+		if locObj.Persistent {
+			locs = append(locs, loc)
+		}
+	}
+	return locs
+}
+
+// PersistentReplicaPolicy is the default eviction rule: a cached file
+// stays until it has been unused for CacheTTL and has been replicated
+// to at least conf.MIN_REPLICA_COUNT persistent Locations. Nodes still
+// short of that count are handed to the replicator instead of evicted.
+type PersistentReplicaPolicy struct {
+	CacheTTL time.Duration
+}
+
+func (p PersistentReplicaPolicy) ShouldEvict(n *Node) (bool, string) {
+	if withinTTL(n, p.CacheTTL) {
+		return false, "within cache TTL"
+	}
+
+	locs := persistentLocations(n)
+	if len(locs) < conf.MIN_REPLICA_COUNT {
+		if replicator.Engine != nil {
+			if err := replicator.Engine.Enqueue(n.Id, locs, n.Priority); err != nil {
+				logger.Errorf("(Reaper-->FileReaper) failed to enqueue %s for replication: %s", n.Id, err.Error())
+			}
+		}
+		return false, fmt.Sprintf("only %d/%d persistent replicas", len(locs), conf.MIN_REPLICA_COUNT)
+	}
+	return true, fmt.Sprintf("%d persistent replicas", len(locs))
+}
+
+// MinReplicaPolicy evicts once a node has at least N persistent
+// Locations, independent of conf.MIN_REPLICA_COUNT. It does not enqueue
+// work with the replicator, so it is intended for deployments that
+// drive replication some other way and only want the reaper to check
+// the result.
+type MinReplicaPolicy struct {
+	N        int
+	CacheTTL time.Duration
+}
+
+func (p MinReplicaPolicy) ShouldEvict(n *Node) (bool, string) {
+	if withinTTL(n, p.CacheTTL) {
+		return false, "within cache TTL"
+	}
+
+	count := len(persistentLocations(n))
+	if count < p.N {
+		return false, fmt.Sprintf("only %d/%d persistent replicas", count, p.N)
+	}
+	return true, fmt.Sprintf("%d persistent replicas", count)
+}
+
+// SizeBoundedLRUPolicy ignores TTL and replica counts entirely: it
+// evicts the least-recently-accessed cached files until the cache's
+// total size is back under MaxBytes.
+type SizeBoundedLRUPolicy struct {
+	MaxBytes int64
+}
+
+func (p SizeBoundedLRUPolicy) ShouldEvict(n *Node) (bool, string) {
+	self, ok := cache.CacheMap[n.Id]
+	if !ok {
+		return false, "not in cache"
+	}
+
+	var total, olderThanSelf int64
+	for id, item := range cache.CacheMap {
+		total += item.Size
+		if id != n.Id && item.Access.Before(self.Access) {
+			olderThanSelf += item.Size
+		}
+	}
+
+	over := total - p.MaxBytes
+	if over <= 0 {
+		return false, "cache under quota"
+	}
+	// self is only needed if everything strictly older than it isn't
+	// already enough to clear the quota on its own; comparing against
+	// the self-inclusive sum instead would overshoot and block eviction
+	// of every item but the very oldest
+	if olderThanSelf >= over {
+		return false, "cache over quota but not among oldest needed"
+	}
+	return true, fmt.Sprintf("cache over quota by %d bytes, evicting oldest", over)
+}
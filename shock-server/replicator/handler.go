@@ -0,0 +1,26 @@
+package replicator
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// StatusHandler serves GET /replication/status: the full replication
+// queue as JSON. It is registered by the main router alongside the
+// other admin-facing endpoints.
+func StatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Engine.Status())
+}
+
+// NodeStatusHandler serves GET /replication/status/{nodeid}: the
+// replication state of a single node.
+func NodeStatusHandler(w http.ResponseWriter, r *http.Request, nodeId string) {
+	item, ok := Engine.NodeStatus(nodeId)
+	if !ok {
+		http.Error(w, "no replication record for node", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
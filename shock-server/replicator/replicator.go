@@ -0,0 +1,304 @@
+// Package replicator enforces cross-location replication of persistent
+// node data. It watches nodes whose number of persistent Locations is
+// below conf.MIN_REPLICA_COUNT and pushes copies to additional backend
+// stores, highest Priority first, before the node is handed off to the
+// file reaper for local eviction.
+package replicator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MG-RAST/Shock/shock-server/conf"
+	"github.com/MG-RAST/Shock/shock-server/db"
+	"github.com/MG-RAST/Shock/shock-server/logger"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const queueCollection = "Replication_Queue"
+
+// queue states
+const (
+	StateQueued     = "queued"
+	StateInProgress = "in_progress"
+	StateComplete   = "complete"
+	StateFailed     = "failed"
+)
+
+// QueueItem is the persistent work-queue record for a single node. It is
+// stored in Mongo following the same query/update pattern as NodeReaper's
+// expiration query, so the queue survives restarts.
+type QueueItem struct {
+	NodeId      string    `bson:"nodeid" json:"nodeid"`
+	Locations   []string  `bson:"locations" json:"locations"` // persistent Location IDs already holding a copy
+	Priority    int       `bson:"priority" json:"priority"`   // node priority, compared against LocationConfig.MinPriority
+	Attempts    int       `bson:"attempts" json:"attempts"`
+	NextAttempt time.Time `bson:"next_attempt" json:"next_attempt"`
+	State       string    `bson:"state" json:"state"`
+	LastError   string    `bson:"last_error,omitempty" json:"last_error,omitempty"`
+}
+
+// Replicator continuously drains the replication queue, pushing node data
+// to additional persistent Locations until MIN_REPLICA_COUNT is met.
+type Replicator struct {
+	Interval   time.Duration
+	NumWorkers int
+
+	mu     sync.RWMutex
+	status map[string]QueueItem // in-memory mirror of queue, keyed by NodeId, for /replication/status
+}
+
+// Engine is the running replicator instance, started by InitReplicator.
+var Engine *Replicator
+
+// InitReplicator creates and starts the global replicator.
+func InitReplicator() {
+	Engine = NewReplicator()
+	go Engine.Handle()
+}
+
+// NewReplicator creates a Replicator with the package defaults.
+func NewReplicator() *Replicator {
+	return &Replicator{
+		Interval:   time.Duration(conf.EXPIRE_WAIT) * time.Minute,
+		NumWorkers: 4,
+		status:     map[string]QueueItem{},
+	}
+}
+
+// Enqueue records that nodeId currently has the given persistent
+// Locations and should be brought up to MIN_REPLICA_COUNT. It is a
+// no-op if the node already meets the requirement.
+func (r *Replicator) Enqueue(nodeId string, locations []string, priority int) error {
+	if len(locations) >= conf.MIN_REPLICA_COUNT {
+		return nil
+	}
+	item := QueueItem{
+		NodeId:      nodeId,
+		Locations:   locations,
+		Priority:    priority,
+		State:       StateQueued,
+		NextAttempt: time.Now(),
+	}
+	_, err := db.Connection.DB.C(queueCollection).Upsert(
+		bson.M{"nodeid": nodeId},
+		bson.M{"$set": item},
+	)
+	if err != nil {
+		return err
+	}
+	r.setStatus(item)
+	return nil
+}
+
+// Handle is the replicator's main loop: it polls the Mongo-backed queue
+// for due items and hands each to a bounded pool of workers.
+func (r *Replicator) Handle() {
+	sem := make(chan struct{}, r.NumWorkers)
+	for {
+		time.Sleep(r.Interval)
+
+		var items []QueueItem
+		query := bson.M{
+			"state":        bson.M{"$in": []string{StateQueued, StateFailed}},
+			"next_attempt": bson.M{"$lte": time.Now()},
+		}
+		err := db.Connection.DB.C(queueCollection).Find(query).All(&items)
+		if err != nil {
+			logger.Errorf("(Replicator) querying queue: %s", err.Error())
+			continue
+		}
+
+		for _, item := range items {
+			item := item
+			sem <- struct{}{}
+			go func() {
+				defer func() { <-sem }()
+				r.process(item)
+			}()
+		}
+	}
+}
+
+// process attempts to replicate a single node to the Locations it is
+// still missing, retrying with exponential backoff on transient errors.
+func (r *Replicator) process(item QueueItem) {
+	item.State = StateInProgress
+	r.save(item)
+
+	targets, err := SelectTargets(item.Locations, item.Priority, conf.MIN_REPLICA_COUNT-len(item.Locations))
+	if err != nil {
+		r.fail(item, err)
+		return
+	}
+
+	for _, loc := range targets {
+		if err := pushToLocation(item.NodeId, loc); err != nil {
+			r.fail(item, fmt.Errorf("pushing to %s: %s", loc.ID, err.Error()))
+			return
+		}
+		if err := addNodeLocation(item.NodeId, loc.ID); err != nil {
+			r.fail(item, fmt.Errorf("recording location %s on node %s: %s", loc.ID, item.NodeId, err.Error()))
+			return
+		}
+		item.Locations = append(item.Locations, loc.ID)
+	}
+
+	item.State = StateComplete
+	item.LastError = ""
+	r.save(item)
+}
+
+// addNodeLocation records that nodeId now has a copy at locationId, so
+// that the next reaper pass (and the next Enqueue) sees the up-to-date
+// replica count instead of re-replicating to the same target forever.
+func addNodeLocation(nodeId, locationId string) error {
+	return db.Connection.DB.C("Nodes").Update(
+		bson.M{"id": nodeId},
+		bson.M{"$addToSet": bson.M{"locations": locationId}},
+	)
+}
+
+// maxBackoffShift bounds the exponent used to compute backoff, so
+// Attempts growing without bound on a persistently-failing location
+// can never wrap the shift back around to a small/zero duration; 12
+// already exceeds the one-hour cap applied below.
+const maxBackoffShift = 12
+
+// fail records the error, bumps the attempt count and schedules the next
+// try with exponential backoff (capped at one hour).
+func (r *Replicator) fail(item QueueItem, err error) {
+	item.Attempts++
+	item.State = StateFailed
+	item.LastError = err.Error()
+
+	shift := item.Attempts
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+	backoff := time.Duration(1<<uint(shift)) * time.Second
+	if backoff > time.Hour {
+		backoff = time.Hour
+	}
+	item.NextAttempt = time.Now().Add(backoff)
+
+	logger.Errorf("(Replicator) node %s: %s (retry in %s)", item.NodeId, err.Error(), backoff.String())
+	r.save(item)
+}
+
+func (r *Replicator) save(item QueueItem) {
+	db.Connection.DB.C(queueCollection).Update(bson.M{"nodeid": item.NodeId}, bson.M{"$set": item})
+	r.setStatus(item)
+}
+
+func (r *Replicator) setStatus(item QueueItem) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status[item.NodeId] = item
+}
+
+// Status returns a snapshot of all known queue items, for the
+// /replication/status endpoint.
+func (r *Replicator) Status() []QueueItem {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	items := make([]QueueItem, 0, len(r.status))
+	for _, item := range r.status {
+		items = append(items, item)
+	}
+	return items
+}
+
+// NodeStatus returns the replication state of a single node.
+func (r *Replicator) NodeStatus(nodeId string) (QueueItem, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	item, ok := r.status[nodeId]
+	return item, ok
+}
+
+// SelectTargets picks up to need additional persistent Locations for a
+// node, skipping any Location whose MinPriority exceeds the node's own
+// priority. Candidates are ordered by Priority descending, then by Tier
+// ascending (prefer cheaper/faster tiers) and Cost ascending as a
+// tie-breaker.
+func SelectTargets(have []string, nodePriority int, need int) ([]*conf.LocationConfig, error) {
+	if need <= 0 {
+		return nil, nil
+	}
+
+	existing := map[string]bool{}
+	for _, id := range have {
+		existing[id] = true
+	}
+
+	candidates := []*conf.LocationConfig{}
+	for _, loc := range conf.Locations() {
+		if existing[loc.ID] || !loc.Persistent {
+			continue
+		}
+		if loc.MinPriority > nodePriority {
+			continue
+		}
+		candidates = append(candidates, loc)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Priority != candidates[j].Priority {
+			return candidates[i].Priority > candidates[j].Priority
+		}
+		if candidates[i].Tier != candidates[j].Tier {
+			return candidates[i].Tier < candidates[j].Tier
+		}
+		return candidates[i].Cost < candidates[j].Cost
+	})
+
+	if len(candidates) < need {
+		return nil, fmt.Errorf("only %d eligible persistent location(s) available, need %d", len(candidates), need)
+	}
+	return candidates[:need], nil
+}
+
+// pushToLocation copies a node's data file from PATH_DATA to the given
+// Location. S3 and TSM stores are expected to expose an HTTP PUT
+// endpoint at Location.URL; this mirrors the simple HTTP-centric
+// transfer already used to serve node downloads.
+func pushToLocation(nodeId string, loc *conf.LocationConfig) error {
+	path := filepath.Join(conf.PATH_DATA, nodeId[0:2], nodeId[2:4], nodeId, nodeId+".data")
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if loc.URL == "" {
+		return errors.New("location has no URL configured")
+	}
+
+	req, err := http.NewRequest("PUT", loc.URL+nodeId, f)
+	if err != nil {
+		return err
+	}
+	if loc.Token != "" {
+		req.Header.Set("Authorization", loc.Token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status from %s: %s", loc.ID, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package replicator
+
+import (
+	"testing"
+
+	"github.com/MG-RAST/Shock/shock-server/conf"
+)
+
+func setTestLocations(t *testing.T, locs ...*conf.LocationConfig) {
+	m := make(map[string]*conf.LocationConfig, len(locs))
+	for _, loc := range locs {
+		m[loc.ID] = loc
+	}
+	conf.SetLocationsForTest(m)
+	t.Cleanup(func() { conf.SetLocationsForTest(nil) })
+}
+
+func TestSelectTargetsOrdersByPriorityThenTierThenCost(t *testing.T) {
+	low := &conf.LocationConfig{ID: "low-priority", Persistent: true, Priority: 10, Tier: 0, Cost: 0}
+	cheap := &conf.LocationConfig{ID: "high-priority-cheap", Persistent: true, Priority: 90, Tier: 5, Cost: 1}
+	expensive := &conf.LocationConfig{ID: "high-priority-expensive", Persistent: true, Priority: 90, Tier: 5, Cost: 9}
+	betterTier := &conf.LocationConfig{ID: "high-priority-better-tier", Persistent: true, Priority: 90, Tier: 3, Cost: 9}
+	setTestLocations(t, low, cheap, expensive, betterTier)
+
+	got, err := SelectTargets(nil, 100, 4)
+	if err != nil {
+		t.Fatalf("SelectTargets: %s", err)
+	}
+
+	want := []string{betterTier.ID, cheap.ID, expensive.ID, low.ID}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d targets, got %d: %v", len(want), len(got), got)
+	}
+	for i, loc := range got {
+		if loc.ID != want[i] {
+			t.Errorf("position %d: expected %s, got %s", i, want[i], loc.ID)
+		}
+	}
+}
+
+func TestSelectTargetsSkipsLocationsAboveNodePriority(t *testing.T) {
+	allowed := &conf.LocationConfig{ID: "allowed", Persistent: true, Priority: 50, MinPriority: 10}
+	tooStrict := &conf.LocationConfig{ID: "too-strict", Persistent: true, Priority: 100, MinPriority: 80}
+	setTestLocations(t, allowed, tooStrict)
+
+	got, err := SelectTargets(nil, 20, 1)
+	if err != nil {
+		t.Fatalf("SelectTargets: %s", err)
+	}
+	if len(got) != 1 || got[0].ID != allowed.ID {
+		t.Fatalf("expected only %q to be eligible for node priority 20, got %v", allowed.ID, got)
+	}
+
+	if _, err := SelectTargets(nil, 20, 2); err == nil {
+		t.Error("expected an error when fewer eligible locations exist than needed")
+	}
+}
+
+func TestSelectTargetsExcludesExistingAndNonPersistentLocations(t *testing.T) {
+	already := &conf.LocationConfig{ID: "already-have", Persistent: true, Priority: 50}
+	cache := &conf.LocationConfig{ID: "not-persistent", Persistent: false, Priority: 100}
+	eligible := &conf.LocationConfig{ID: "eligible", Persistent: true, Priority: 10}
+	setTestLocations(t, already, cache, eligible)
+
+	got, err := SelectTargets([]string{already.ID}, 100, 1)
+	if err != nil {
+		t.Fatalf("SelectTargets: %s", err)
+	}
+	if len(got) != 1 || got[0].ID != eligible.ID {
+		t.Fatalf("expected only %q to be selected, got %v", eligible.ID, got)
+	}
+}
+
+func TestSelectTargetsNeedsZeroOrLess(t *testing.T) {
+	got, err := SelectTargets(nil, 100, 0)
+	if err != nil {
+		t.Fatalf("SelectTargets: %s", err)
+	}
+	if got != nil {
+		t.Errorf("expected no targets when need <= 0, got %v", got)
+	}
+}